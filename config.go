@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/go-yaml/yaml"
+)
+
+// Config describes a full pgen run driven by a single YAML file, as an
+// alternative to the -model/-out-go/-dialect/... flags: one or more model
+// files are parsed and merged into a single Metadata tree (so enum and
+// table references across files resolve), then rendered to every output
+// the config asks for.
+type Config struct {
+	Models        []string `yaml:"models"`
+	OutSQL        string   `yaml:"out_sql"`
+	OutGo         string   `yaml:"out_go"`
+	MigrationsDir string   `yaml:"migrations_dir"`
+	FixturesDir   string   `yaml:"fixtures_dir"`
+	Dialect       string   `yaml:"dialect"`
+	Package       string   `yaml:"package"`
+	Naming        string   `yaml:"naming"` // "snake_case" (default) or "camelCase"
+	DefaultNow    string   `yaml:"default_now"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	if err := checkFile(path); err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+
+	if len(c.Models) == 0 {
+		return nil, fmt.Errorf("%s: 'models' should list at least one model file", path)
+	}
+
+	return c, nil
+}
+
+// runConfig executes the run described by the config file at path. When
+// prevPath is set, the merged models are diffed against it and a
+// migration is written to c.MigrationsDir (falling back to
+// migrationsDirFlag) instead of rendering the SQL/Go outputs.
+func runConfig(path string, prevPath string, withDown bool, migrationsDirFlag string) error {
+	c, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	p := parser{
+		Data: &Metadata{
+			Enums:  []*Enum{},
+			Tables: []*Table{},
+		},
+		DefaultNow: c.DefaultNow,
+	}
+
+	var rawModels []yaml.MapSlice
+	for _, m := range c.Models {
+		if err := checkFile(m); err != nil {
+			return err
+		}
+
+		raw, err := readFile(m)
+		if err != nil {
+			return err
+		}
+
+		rawModels = append(rawModels, raw)
+	}
+
+	// enums and tables are parsed across all files before refs are
+	// validated, so a table (or enum-typed field) in one file may
+	// reference a table (or enum) declared in another file, regardless
+	// of the files' order in c.Models
+	for i, raw := range rawModels {
+		if err := p.parseEnums(raw); err != nil {
+			return fmt.Errorf("%s: %s", c.Models[i], err.Error())
+		}
+	}
+
+	for i, raw := range rawModels {
+		if err := p.parseTables(raw); err != nil {
+			return fmt.Errorf("%s: %s", c.Models[i], err.Error())
+		}
+	}
+
+	if err := p.validateRefs(); err != nil {
+		return err
+	}
+
+	data := p.Data
+
+	if prevPath != "" {
+		warnIfDialectIgnoredByDiff(c.Dialect)
+
+		prevData, err := parseModel(prevPath)
+		if err != nil {
+			return err
+		}
+
+		dir := c.MigrationsDir
+		if dir == "" {
+			dir = migrationsDirFlag
+		}
+
+		return writeMigration(prevData, data, dir, withDown)
+	}
+
+	d, err := dialectByName(c.Dialect)
+	if err != nil {
+		return err
+	}
+
+	if c.OutSQL != "" {
+		if err := os.MkdirAll(filepath.Dir(c.OutSQL), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.Create(c.OutSQL)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := render(data, f, d); err != nil {
+			return err
+		}
+	}
+
+	if c.OutGo != "" {
+		pkg := c.Package
+		if pkg == "" {
+			pkg = "model"
+		}
+
+		if err := writeGo(data, c.OutGo, pkg, c.Naming, d); err != nil {
+			return err
+		}
+	}
+
+	if c.FixturesDir != "" {
+		if err := writeFixtures(data, c.FixturesDir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect renders the SQL for a specific database backend. Metadata stays
+// dialect-agnostic; render consults a Dialect for every piece of syntax
+// that differs between backends (types, quoting, enum/index DDL).
+type Dialect interface {
+	// MapType returns the column type keyword for t.
+	MapType(t *Type) string
+	// RenderEnum renders the standalone type declaration for e, or ""
+	// when the dialect has no such concept (e.g. MySQL, which renders
+	// enums inline on the column instead).
+	RenderEnum(e *Enum) string
+	// RenderCreateTable renders the full `create table` statement for t.
+	RenderCreateTable(t *Table) string
+	// RenderCreateIndex renders a single index or unique constraint on t.
+	RenderCreateIndex(t *Table, index Index, unique bool) string
+	// QuoteIdent quotes name using the dialect's identifier quoting rules.
+	QuoteIdent(name string) string
+	// DefaultLiteral renders f's default value as a SQL literal.
+	DefaultLiteral(f *Field) string
+	// Placeholder renders the bind placeholder for the n'th (1-based)
+	// positional query parameter.
+	Placeholder(n int) string
+}
+
+func dialectByName(name string) (Dialect, error) {
+	switch name {
+	case "", "postgres":
+		return dialectPostgres{}, nil
+	case "mysql":
+		return dialectMySQL{}, nil
+	case "sqlite":
+		return dialectSQLite{}, nil
+	default:
+		return nil, fmt.Errorf("unknown dialect: %s", name)
+	}
+}
+
+func indexName(t *Table, index Index, unique bool) string {
+	suffix := "idx"
+	if unique {
+		suffix = "key"
+	}
+	return fmt.Sprintf("%s_%s_%s", t.Name, strings.Join(index, "_"), suffix)
+}
+
+// ---- postgres ---------------------------------------------------------
+
+type dialectPostgres struct{}
+
+func (dialectPostgres) QuoteIdent(name string) string { return name }
+
+func (dialectPostgres) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+func (dialectPostgres) MapType(t *Type) string { return t.T }
+
+func (dialectPostgres) DefaultLiteral(f *Field) string {
+	switch f.Type.T {
+	case DataTypeVarchar:
+		return fmt.Sprintf("'%s'", f.Default.(string))
+	case DataTypeTimestamptz:
+		return fmt.Sprintf("%s", f.Default.(string))
+	case DataTypeInteger, DataTypeBigint:
+		return fmt.Sprintf("%d", f.Default.(int))
+	case DataTypeBool:
+		return fmt.Sprintf("%t", f.Default.(bool))
+	default:
+		if f.Type.IsEnum {
+			return fmt.Sprintf("'%s'", f.Default.(string))
+		}
+		return fmt.Sprintf("%v", f.Default)
+	}
+}
+
+func (d dialectPostgres) RenderEnum(e *Enum) string {
+	g := &gen{}
+	g.P("create type", e.Name, "as enum(")
+	for j, val := range e.Values {
+		if j > 0 {
+			g.P(", ")
+		}
+		g.Pf("'%s'", val)
+	}
+	g.P(");").Ln()
+
+	if e.Comment != "" {
+		g.Pf("comment on type %s is '%s';", e.Name, e.Comment).Ln()
+	}
+
+	return g.String()
+}
+
+func (d dialectPostgres) RenderCreateTable(t *Table) string {
+	g := &gen{}
+	g.P("create table if not exists", t.Name, "(").Ln()
+
+	for j, f := range t.Fields {
+		g.P("  ")
+		renderFieldDDL(g, f)
+
+		if j < len(t.Fields)-1 {
+			g.P(",")
+		}
+		g.Ln()
+	}
+
+	g.P(");").Ln()
+
+	if t.Comment != "" {
+		g.Pf("comment on table %s is '%s';", t.Name, t.Comment).Ln()
+	}
+
+	for _, f := range t.Fields {
+		if f.Comment != "" {
+			g.Pf("comment on column %s.%s is '%s';", t.Name, f.Name, f.Comment).Ln()
+		}
+	}
+
+	return g.String()
+}
+
+func (d dialectPostgres) RenderCreateIndex(t *Table, index Index, unique bool) string {
+	g := &gen{}
+	kw := "create index"
+	if unique {
+		kw = "create unique index"
+	}
+	g.Pf("%s %s on %s (%s);", kw, indexName(t, index, unique), t.Name, strings.Join(index, ", ")).Ln()
+	return g.String()
+}
+
+// ---- mysql --------------------------------------------------------------
+
+type dialectMySQL struct{}
+
+func (dialectMySQL) QuoteIdent(name string) string { return "`" + name + "`" }
+
+func (dialectMySQL) Placeholder(n int) string { return "?" }
+
+func (dialectMySQL) MapType(t *Type) string {
+	if t.IsEnum {
+		var vals []string
+		for _, v := range t.EnumValues {
+			vals = append(vals, fmt.Sprintf("'%s'", v))
+		}
+		return fmt.Sprintf("enum(%s)", strings.Join(vals, ", "))
+	}
+
+	switch t.T {
+	case DataTypeTimestamptz:
+		return "timestamp"
+	case "jsonb":
+		return "json"
+	case DataTypeSerial:
+		return "bigint auto_increment"
+	default:
+		return t.T
+	}
+}
+
+func (d dialectMySQL) DefaultLiteral(f *Field) string {
+	switch f.Type.T {
+	case DataTypeVarchar, DataTypeText:
+		return fmt.Sprintf("'%s'", f.Default.(string))
+	case DataTypeTimestamptz:
+		if f.Default.(string) == "current_timestamp" {
+			return "current_timestamp"
+		}
+		return fmt.Sprintf("'%s'", f.Default.(string))
+	case DataTypeInteger, DataTypeBigint:
+		return fmt.Sprintf("%d", f.Default.(int))
+	case DataTypeBool:
+		return fmt.Sprintf("%t", f.Default.(bool))
+	default:
+		if f.Type.IsEnum {
+			return fmt.Sprintf("'%s'", f.Default.(string))
+		}
+		return fmt.Sprintf("%v", f.Default)
+	}
+}
+
+// RenderEnum is a no-op for MySQL: enums have no standalone type and are
+// rendered inline on the column by MapType instead.
+func (dialectMySQL) RenderEnum(e *Enum) string { return "" }
+
+func (d dialectMySQL) renderColumn(g *gen, f *Field) {
+	g.Pf("`%s` %s", f.Name, d.MapType(f.Type))
+
+	if f.Size > 0 && f.Type.T == DataTypeVarchar {
+		g.Pf("(%d)", f.Size)
+	}
+
+	if f.Default != nil {
+		g.Pf(" default %s", d.DefaultLiteral(f))
+	}
+
+	if !f.Nullable && !f.PK {
+		g.P(" not null")
+	}
+
+	if f.PK {
+		g.P(" primary key")
+	}
+
+	// MySQL/InnoDB parses but silently ignores a column-level REFERENCES
+	// clause; the foreign key is emitted as a trailing table-level
+	// constraint by RenderCreateTable instead.
+
+	if f.Comment != "" {
+		g.Pf(" comment '%s'", f.Comment)
+	}
+}
+
+func (d dialectMySQL) RenderCreateTable(t *Table) string {
+	g := &gen{}
+	g.Pf("create table if not exists `%s` (", t.Name).Ln()
+
+	var fks []*Field
+	for _, f := range t.Fields {
+		if f.Ref != nil {
+			fks = append(fks, f)
+		}
+	}
+
+	for j, f := range t.Fields {
+		g.P("  ")
+		d.renderColumn(g, f)
+
+		if j < len(t.Fields)-1 || len(fks) > 0 {
+			g.P(",")
+		}
+		g.Ln()
+	}
+
+	for j, f := range fks {
+		g.Pf("  %s", renderFKConstraint(d, t, f))
+		if j < len(fks)-1 {
+			g.P(",")
+		}
+		g.Ln()
+	}
+
+	g.P(")")
+	if t.Comment != "" {
+		g.Pf(" comment '%s'", t.Comment)
+	}
+	g.P(";").Ln()
+
+	return g.String()
+}
+
+func (d dialectMySQL) RenderCreateIndex(t *Table, index Index, unique bool) string {
+	g := &gen{}
+	kw := "create index"
+	if unique {
+		kw = "create unique index"
+	}
+
+	var cols []string
+	for _, c := range index {
+		cols = append(cols, "`"+c+"`")
+	}
+
+	g.Pf("%s %s on `%s` (%s);", kw, indexName(t, index, unique), t.Name, strings.Join(cols, ", ")).Ln()
+	return g.String()
+}
+
+// ---- sqlite -------------------------------------------------------------
+
+type dialectSQLite struct{}
+
+func (dialectSQLite) QuoteIdent(name string) string { return `"` + name + `"` }
+
+func (dialectSQLite) Placeholder(n int) string { return "?" }
+
+func (dialectSQLite) MapType(t *Type) string {
+	if t.IsEnum {
+		return "text"
+	}
+
+	switch t.T {
+	case DataTypeVarchar, DataTypeText:
+		return "text"
+	case DataTypeInteger, DataTypeBigint, DataTypeSerial:
+		return "integer"
+	case DataTypeBool:
+		return "integer"
+	case DataTypeDouble:
+		return "real"
+	case DataTypeTime, DataTypeTimestamptz:
+		return "text"
+	case "jsonb":
+		return "text"
+	default:
+		return t.T
+	}
+}
+
+func (d dialectSQLite) DefaultLiteral(f *Field) string {
+	switch f.Type.T {
+	case DataTypeTimestamptz:
+		if f.Default.(string) == "current_timestamp" {
+			return "current_timestamp"
+		}
+		return fmt.Sprintf("'%s'", f.Default.(string))
+	case DataTypeVarchar, DataTypeText:
+		return fmt.Sprintf("'%s'", f.Default.(string))
+	case DataTypeBool:
+		if f.Default.(bool) {
+			return "1"
+		}
+		return "0"
+	case DataTypeInteger, DataTypeBigint:
+		return fmt.Sprintf("%d", f.Default.(int))
+	default:
+		if f.Type.IsEnum {
+			return fmt.Sprintf("'%s'", f.Default.(string))
+		}
+		return fmt.Sprintf("%v", f.Default)
+	}
+}
+
+// RenderEnum is a no-op for SQLite: there is no enum/custom type concept,
+// enum columns are rendered as plain text by MapType instead.
+func (dialectSQLite) RenderEnum(e *Enum) string { return "" }
+
+func (d dialectSQLite) renderColumn(g *gen, f *Field) {
+	if f.PK && f.Type.T == DataTypeSerial {
+		// sqlite's rowid-aliasing integer primary key is the idiomatic
+		// stand-in for a serial/auto-increment column.
+		g.Pf(`"%s" integer primary key autoincrement`, f.Name)
+		g.P(renderRefClause(d, f))
+		return
+	}
+
+	g.Pf(`"%s" %s`, f.Name, d.MapType(f.Type))
+
+	if f.Default != nil {
+		g.Pf(" default %s", d.DefaultLiteral(f))
+	}
+
+	if !f.Nullable && !f.PK {
+		g.P(" not null")
+	}
+
+	if f.PK {
+		g.P(" primary key")
+	}
+
+	g.P(renderRefClause(d, f))
+}
+
+func (d dialectSQLite) RenderCreateTable(t *Table) string {
+	g := &gen{}
+	g.Pf(`create table if not exists "%s" (`, t.Name).Ln()
+
+	for j, f := range t.Fields {
+		g.P("  ")
+		d.renderColumn(g, f)
+
+		if j < len(t.Fields)-1 {
+			g.P(",")
+		}
+		g.Ln()
+	}
+
+	g.P(");").Ln()
+	return g.String()
+}
+
+func (d dialectSQLite) RenderCreateIndex(t *Table, index Index, unique bool) string {
+	g := &gen{}
+	kw := "create index"
+	if unique {
+		kw = "create unique index"
+	}
+
+	var cols []string
+	for _, c := range index {
+		cols = append(cols, `"`+c+`"`)
+	}
+
+	g.Pf(`%s %s on "%s" (%s);`, kw, indexName(t, index, unique), t.Name, strings.Join(cols, ", ")).Ln()
+	return g.String()
+}
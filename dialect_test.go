@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDialectByName(t *testing.T) {
+	cases := []struct {
+		name    string
+		want    Dialect
+		wantErr bool
+	}{
+		{name: "", want: dialectPostgres{}},
+		{name: "postgres", want: dialectPostgres{}},
+		{name: "mysql", want: dialectMySQL{}},
+		{name: "sqlite", want: dialectSQLite{}},
+		{name: "oracle", wantErr: true},
+	}
+
+	for _, c := range cases {
+		d, err := dialectByName(c.name)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("dialectByName(%q): expected error, got nil", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("dialectByName(%q): unexpected error: %v", c.name, err)
+		}
+		if d != c.want {
+			t.Errorf("dialectByName(%q) = %#v, want %#v", c.name, d, c.want)
+		}
+	}
+}
+
+func TestDialectQuoteIdent(t *testing.T) {
+	cases := []struct {
+		d    Dialect
+		want string
+	}{
+		{dialectPostgres{}, "users"},
+		{dialectMySQL{}, "`users`"},
+		{dialectSQLite{}, `"users"`},
+	}
+
+	for _, c := range cases {
+		if got := c.d.QuoteIdent("users"); got != c.want {
+			t.Errorf("%T.QuoteIdent(\"users\") = %q, want %q", c.d, got, c.want)
+		}
+	}
+}
+
+func TestDialectPlaceholder(t *testing.T) {
+	cases := []struct {
+		d    Dialect
+		n    int
+		want string
+	}{
+		{dialectPostgres{}, 1, "$1"},
+		{dialectPostgres{}, 2, "$2"},
+		{dialectMySQL{}, 1, "?"},
+		{dialectSQLite{}, 3, "?"},
+	}
+
+	for _, c := range cases {
+		if got := c.d.Placeholder(c.n); got != c.want {
+			t.Errorf("%T.Placeholder(%d) = %q, want %q", c.d, c.n, got, c.want)
+		}
+	}
+}
+
+func TestDialectMapType(t *testing.T) {
+	enum := &Type{T: "role_enum", IsEnum: true, EnumValues: []string{"admin", "member"}}
+
+	cases := []struct {
+		d    Dialect
+		t    *Type
+		want string
+	}{
+		{dialectPostgres{}, &Type{T: DataTypeBigint}, "bigint"},
+		{dialectMySQL{}, &Type{T: DataTypeSerial}, "bigint auto_increment"},
+		{dialectMySQL{}, &Type{T: DataTypeTimestamptz}, "timestamp"},
+		{dialectMySQL{}, enum, "enum('admin', 'member')"},
+		{dialectSQLite{}, &Type{T: DataTypeVarchar}, "text"},
+		{dialectSQLite{}, &Type{T: DataTypeSerial}, "integer"},
+		{dialectSQLite{}, enum, "text"},
+	}
+
+	for _, c := range cases {
+		if got := c.d.MapType(c.t); got != c.want {
+			t.Errorf("%T.MapType(%q) = %q, want %q", c.d, c.t.T, got, c.want)
+		}
+	}
+}
+
+func TestDialectRenderCreateTableFK(t *testing.T) {
+	tbl := &Table{
+		Name: "profiles",
+		Fields: []*Field{
+			{Name: "user_id", Type: &Type{T: DataTypeSerial}, PK: true,
+				Ref: &Reference{Table: "users", Column: "id", OnDelete: "cascade"}},
+		},
+	}
+
+	// MySQL/InnoDB silently ignores an inline column-level REFERENCES
+	// clause, so the FK must come out as a trailing table-level
+	// constraint, not inline on the column.
+	mysqlDDL := (dialectMySQL{}).RenderCreateTable(tbl)
+	if strings.Contains(mysqlDDL, "`user_id` bigint auto_increment primary key references") {
+		t.Errorf("mysql DDL should not inline references on the column: %s", mysqlDDL)
+	}
+	if !strings.Contains(mysqlDDL, "constraint profiles_user_id_fkey foreign key (`user_id`) references `users`(`id`) on delete cascade") {
+		t.Errorf("mysql DDL missing table-level FK constraint: %s", mysqlDDL)
+	}
+
+	// SQLite's serial-PK fast path must still carry the ref clause.
+	sqliteDDL := (dialectSQLite{}).RenderCreateTable(tbl)
+	if !strings.Contains(sqliteDDL, `"user_id" integer primary key autoincrement references "users"("id") on delete cascade`) {
+		t.Errorf("sqlite DDL missing ref clause on serial PK: %s", sqliteDDL)
+	}
+
+	// Postgres renders the FK inline, which InnoDB-style silent-ignore
+	// does not apply to.
+	pgDDL := (dialectPostgres{}).RenderCreateTable(tbl)
+	if !strings.Contains(pgDDL, "references users(id) on delete cascade") {
+		t.Errorf("postgres DDL missing ref clause: %s", pgDDL)
+	}
+}
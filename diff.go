@@ -0,0 +1,381 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// writeMigration diffs old against new and writes the resulting migration
+// file(s) (numbered per golang-migrate/goose conventions) into dir. A down
+// migration is written alongside the up migration when down is true.
+func writeMigration(old, new *Metadata, dir string, down bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	up, dn := diff(old, new)
+
+	seq := time.Now().Format("20060102150405")
+
+	if err := os.WriteFile(filepath.Join(dir, seq+"_migration.up.sql"), []byte(up.String()), 0644); err != nil {
+		return err
+	}
+
+	if down {
+		if err := os.WriteFile(filepath.Join(dir, seq+"_migration.down.sql"), []byte(dn.String()), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diff walks old and new and returns the up and down migration scripts
+// that take a database from old's shape to new's (up) and back (down).
+func diff(old, new *Metadata) (up, dn *gen) {
+	up, dn = &gen{}, &gen{}
+
+	diffEnums(up, dn, old.Enums, new.Enums)
+	diffTables(up, dn, old.Tables, new.Tables)
+
+	return up, dn
+}
+
+func diffEnums(up, dn *gen, oldEnums, newEnums []*Enum) {
+	for _, e := range newEnums {
+		old := findEnum(oldEnums, e.Name)
+		if old == nil {
+			up.Pf("create type %s as enum(", e.Name)
+			for j, val := range e.Values {
+				if j > 0 {
+					up.P(", ")
+				}
+				up.Pf("'%s'", val)
+			}
+			up.P(");").Ln()
+			dn.Pf("drop type %s;", e.Name).Ln()
+			continue
+		}
+
+		oldValues := map[string]bool{}
+		for _, v := range old.Values {
+			oldValues[v] = true
+		}
+		for _, v := range e.Values {
+			if !oldValues[v] {
+				up.Pf("alter type %s add value '%s';", e.Name, v).Ln()
+			}
+		}
+
+		newValues := map[string]bool{}
+		for _, v := range e.Values {
+			newValues[v] = true
+		}
+		for _, v := range old.Values {
+			if !newValues[v] {
+				up.Pf("-- warning: postgres can not drop enum value '%s' from type %s, leaving it in place", v, e.Name).Ln()
+			}
+		}
+	}
+
+	for _, e := range oldEnums {
+		if findEnum(newEnums, e.Name) == nil {
+			up.Pf("drop type %s;", e.Name).Ln()
+			dn.Pf("create type %s as enum(", e.Name)
+			for j, val := range e.Values {
+				if j > 0 {
+					dn.P(", ")
+				}
+				dn.Pf("'%s'", val)
+			}
+			dn.P(");").Ln()
+		}
+	}
+}
+
+func diffTables(up, dn *gen, oldTables, newTables []*Table) {
+	for _, t := range newTables {
+		old := matchOldTable(oldTables, t)
+		if old == nil {
+			renderCreateTable(up, t)
+			dn.Pf("drop table %s;", t.Name).Ln()
+			continue
+		}
+
+		if t.RenamedFrom != "" && t.RenamedFrom != old.Name {
+			up.Pf("alter table %s rename to %s;", old.Name, t.Name).Ln()
+			dn.Pf("alter table %s rename to %s;", t.Name, old.Name).Ln()
+		}
+
+		diffColumns(up, dn, old, t)
+		diffIndexes(up, dn, old, t)
+	}
+
+	for _, t := range oldTables {
+		if matchNewTable(newTables, t) == nil {
+			up.Pf("drop table %s;", t.Name).Ln()
+			renderCreateTable(dn, t)
+		}
+	}
+}
+
+func diffColumns(up, dn *gen, old, new *Table) {
+	for _, f := range new.Fields {
+		oldField := matchOldField(old.Fields, f)
+		if oldField == nil {
+			up.Pf("alter table %s add column ", new.Name)
+			renderFieldDDL(up, f)
+			up.P(";").Ln()
+
+			dn.Pf("alter table %s drop column %s;", new.Name, f.Name).Ln()
+			continue
+		}
+
+		if f.RenamedFrom != "" && f.RenamedFrom != oldField.Name {
+			up.Pf("alter table %s rename column %s to %s;", new.Name, oldField.Name, f.Name).Ln()
+			dn.Pf("alter table %s rename column %s to %s;", new.Name, f.Name, oldField.Name).Ln()
+		}
+
+		diffColumnAttrs(up, dn, new.Name, oldField, f)
+		diffRef(up, dn, new.Name, oldField, f)
+	}
+
+	for _, f := range old.Fields {
+		if matchNewField(new.Fields, f) == nil {
+			up.Pf("alter table %s drop column %s;", new.Name, f.Name).Ln()
+			dn.Pf("alter table %s add column ", new.Name)
+			renderFieldDDL(dn, f)
+			dn.P(";").Ln()
+		}
+	}
+}
+
+func diffColumnAttrs(up, dn *gen, table string, old, new *Field) {
+	if old.Type.T != new.Type.T || old.Size != new.Size {
+		up.Pf("alter table %s alter column %s type %s", table, new.Name, new.Type.T)
+		if new.Size > 0 && new.Type.T == DataTypeVarchar {
+			up.Pf("(%d)", new.Size)
+		}
+		up.P(";").Ln()
+
+		dn.Pf("alter table %s alter column %s type %s", table, old.Name, old.Type.T)
+		if old.Size > 0 && old.Type.T == DataTypeVarchar {
+			dn.Pf("(%d)", old.Size)
+		}
+		dn.P(";").Ln()
+	}
+
+	if old.Nullable != new.Nullable {
+		if new.Nullable {
+			up.Pf("alter table %s alter column %s drop not null;", table, new.Name).Ln()
+			dn.Pf("alter table %s alter column %s set not null;", table, new.Name).Ln()
+		} else {
+			up.Pf("alter table %s alter column %s set not null;", table, new.Name).Ln()
+			dn.Pf("alter table %s alter column %s drop not null;", table, new.Name).Ln()
+		}
+	}
+
+	if !defaultsEqual(old, new) {
+		if new.Default == nil {
+			up.Pf("alter table %s alter column %s drop default;", table, new.Name).Ln()
+		} else {
+			up.Pf("alter table %s alter column %s set default %s;", table, new.Name, defaultLiteral(new)).Ln()
+		}
+
+		if old.Default == nil {
+			dn.Pf("alter table %s alter column %s drop default;", table, old.Name).Ln()
+		} else {
+			dn.Pf("alter table %s alter column %s set default %s;", table, old.Name, defaultLiteral(old)).Ln()
+		}
+	}
+}
+
+func defaultsEqual(old, new *Field) bool {
+	if old.Default == nil && new.Default == nil {
+		return true
+	}
+	if old.Default == nil || new.Default == nil {
+		return false
+	}
+	return old.Default == new.Default
+}
+
+func defaultLiteral(f *Field) string {
+	switch f.Type.T {
+	case DataTypeVarchar:
+		return fmt.Sprintf("'%s'", f.Default.(string))
+	case DataTypeTimestamptz:
+		return fmt.Sprintf("%s", f.Default.(string))
+	case DataTypeInteger, DataTypeBigint:
+		return fmt.Sprintf("%d", f.Default.(int))
+	case DataTypeBool:
+		return fmt.Sprintf("%t", f.Default.(bool))
+	default:
+		if f.Type.IsEnum {
+			return fmt.Sprintf("'%s'", f.Default.(string))
+		}
+		return fmt.Sprintf("%v", f.Default)
+	}
+}
+
+// diffRef emits add/drop foreign key constraint statements when new's
+// 'ref'/'fk' attribute differs from old's. A changed ref is handled as a
+// drop of the old constraint followed by an add of the new one.
+func diffRef(up, dn *gen, table string, old, new *Field) {
+	if refsEqual(old.Ref, new.Ref) {
+		return
+	}
+
+	oldName := fkConstraintName(table, old.Name)
+	newName := fkConstraintName(table, new.Name)
+
+	if old.Ref != nil {
+		up.Pf("alter table %s drop constraint %s;", table, oldName).Ln()
+	}
+	if new.Ref != nil {
+		up.Pf("alter table %s add constraint %s foreign key (%s) references %s(%s)",
+			table, newName, new.Name, new.Ref.Table, new.Ref.Column)
+		if new.Ref.OnDelete != "" {
+			up.Pf(" on delete %s", new.Ref.OnDelete)
+		}
+		if new.Ref.OnUpdate != "" {
+			up.Pf(" on update %s", new.Ref.OnUpdate)
+		}
+		up.P(";").Ln()
+	}
+
+	if new.Ref != nil {
+		dn.Pf("alter table %s drop constraint %s;", table, newName).Ln()
+	}
+	if old.Ref != nil {
+		dn.Pf("alter table %s add constraint %s foreign key (%s) references %s(%s)",
+			table, oldName, old.Name, old.Ref.Table, old.Ref.Column)
+		if old.Ref.OnDelete != "" {
+			dn.Pf(" on delete %s", old.Ref.OnDelete)
+		}
+		if old.Ref.OnUpdate != "" {
+			dn.Pf(" on update %s", old.Ref.OnUpdate)
+		}
+		dn.P(";").Ln()
+	}
+}
+
+func refsEqual(a, b *Reference) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func fkConstraintName(table, column string) string {
+	return fmt.Sprintf("%s_%s_fkey", table, column)
+}
+
+func diffIndexes(up, dn *gen, old, new *Table) {
+	diffIndexSet(up, dn, new.Name, old.Uniques, new.Uniques, true)
+	diffIndexSet(up, dn, new.Name, old.Indexes, new.Indexes, false)
+}
+
+func diffIndexSet(up, dn *gen, table string, oldIdx, newIdx []Index, unique bool) {
+	suffix := "idx"
+	createKw := "create index"
+	if unique {
+		suffix = "key"
+		createKw = "create unique index"
+	}
+
+	for _, idx := range newIdx {
+		if !containsIndex(oldIdx, idx) {
+			name := fmt.Sprintf("%s_%s_%s", table, strings.Join(idx, "_"), suffix)
+			up.Pf("%s %s on %s (%s);", createKw, name, table, strings.Join(idx, ", ")).Ln()
+			dn.Pf("drop index %s;", name).Ln()
+		}
+	}
+
+	for _, idx := range oldIdx {
+		if !containsIndex(newIdx, idx) {
+			name := fmt.Sprintf("%s_%s_%s", table, strings.Join(idx, "_"), suffix)
+			up.Pf("drop index %s;", name).Ln()
+			dn.Pf("%s %s on %s (%s);", createKw, name, table, strings.Join(idx, ", ")).Ln()
+		}
+	}
+}
+
+func containsIndex(set []Index, idx Index) bool {
+	for _, i := range set {
+		if strings.Join(i, ",") == strings.Join(idx, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+// renderCreateTable emits a full `create table` statement for t, matching
+// render's table DDL, for use in diff mode when a table is newly added.
+func renderCreateTable(g *gen, t *Table) {
+	g.P("create table if not exists", t.Name, "(").Ln()
+
+	for j, f := range t.Fields {
+		g.P("  ")
+		renderFieldDDL(g, f)
+
+		if j < len(t.Fields)-1 {
+			g.P(",")
+		}
+		g.Ln()
+	}
+
+	g.P(");").Ln()
+}
+
+func findEnum(enums []*Enum, name string) *Enum {
+	for _, e := range enums {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// matchOldTable finds the table in oldTables that t was generated from,
+// either by matching name or by t's renamed_from attribute.
+func matchOldTable(oldTables []*Table, t *Table) *Table {
+	for _, old := range oldTables {
+		if old.Name == t.Name || (t.RenamedFrom != "" && old.Name == t.RenamedFrom) {
+			return old
+		}
+	}
+	return nil
+}
+
+// matchNewTable finds the table in newTables that descends from t, either
+// by name or by the new table's renamed_from attribute.
+func matchNewTable(newTables []*Table, t *Table) *Table {
+	for _, n := range newTables {
+		if n.Name == t.Name || (n.RenamedFrom != "" && n.RenamedFrom == t.Name) {
+			return n
+		}
+	}
+	return nil
+}
+
+func matchOldField(oldFields []*Field, f *Field) *Field {
+	for _, old := range oldFields {
+		if old.Name == f.Name || (f.RenamedFrom != "" && old.Name == f.RenamedFrom) {
+			return old
+		}
+	}
+	return nil
+}
+
+func matchNewField(newFields []*Field, f *Field) *Field {
+	for _, n := range newFields {
+		if n.Name == f.Name || (n.RenamedFrom != "" && n.RenamedFrom == f.Name) {
+			return n
+		}
+	}
+	return nil
+}
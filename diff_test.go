@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffColumnAttrs(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new *Field
+		wantUp   string
+		wantDn   string
+	}{
+		{
+			name:   "type change",
+			old:    &Field{Name: "n", Type: &Type{T: DataTypeInteger}},
+			new:    &Field{Name: "n", Type: &Type{T: DataTypeBigint}},
+			wantUp: "alter table t alter column n type bigint;\n",
+			wantDn: "alter table t alter column n type integer;\n",
+		},
+		{
+			name:   "size change",
+			old:    &Field{Name: "n", Type: &Type{T: DataTypeVarchar}, Size: 50},
+			new:    &Field{Name: "n", Type: &Type{T: DataTypeVarchar}, Size: 100},
+			wantUp: "alter table t alter column n type varchar(100);\n",
+			wantDn: "alter table t alter column n type varchar(50);\n",
+		},
+		{
+			name:   "becomes nullable",
+			old:    &Field{Name: "n", Type: &Type{T: DataTypeInteger}, Nullable: false},
+			new:    &Field{Name: "n", Type: &Type{T: DataTypeInteger}, Nullable: true},
+			wantUp: "alter table t alter column n drop not null;\n",
+			wantDn: "alter table t alter column n set not null;\n",
+		},
+		{
+			name:   "becomes not null",
+			old:    &Field{Name: "n", Type: &Type{T: DataTypeInteger}, Nullable: true},
+			new:    &Field{Name: "n", Type: &Type{T: DataTypeInteger}, Nullable: false},
+			wantUp: "alter table t alter column n set not null;\n",
+			wantDn: "alter table t alter column n drop not null;\n",
+		},
+		{
+			name:   "default added",
+			old:    &Field{Name: "n", Type: &Type{T: DataTypeVarchar}},
+			new:    &Field{Name: "n", Type: &Type{T: DataTypeVarchar}, Default: "foo"},
+			wantUp: "alter table t alter column n set default 'foo';\n",
+			wantDn: "alter table t alter column n drop default;\n",
+		},
+		{
+			name:   "default dropped",
+			old:    &Field{Name: "n", Type: &Type{T: DataTypeVarchar}, Default: "foo"},
+			new:    &Field{Name: "n", Type: &Type{T: DataTypeVarchar}},
+			wantUp: "alter table t alter column n drop default;\n",
+			wantDn: "alter table t alter column n set default 'foo';\n",
+		},
+		{
+			name:   "no change",
+			old:    &Field{Name: "n", Type: &Type{T: DataTypeInteger}},
+			new:    &Field{Name: "n", Type: &Type{T: DataTypeInteger}},
+			wantUp: "",
+			wantDn: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			up, dn := &gen{}, &gen{}
+			diffColumnAttrs(up, dn, "t", c.old, c.new)
+
+			if got := up.String(); got != c.wantUp {
+				t.Errorf("up = %q, want %q", got, c.wantUp)
+			}
+			if got := dn.String(); got != c.wantDn {
+				t.Errorf("dn = %q, want %q", got, c.wantDn)
+			}
+		})
+	}
+}
+
+func TestDiffRef(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new *Field
+		wantUp   string
+		wantDn   string
+	}{
+		{
+			name:   "ref added",
+			old:    &Field{Name: "author_id", Type: &Type{T: DataTypeBigint}},
+			new:    &Field{Name: "author_id", Type: &Type{T: DataTypeBigint}, Ref: &Reference{Table: "users", Column: "id"}},
+			wantUp: "alter table posts add constraint posts_author_id_fkey foreign key (author_id) references users(id);\n",
+			wantDn: "alter table posts drop constraint posts_author_id_fkey;\n",
+		},
+		{
+			name:   "ref removed",
+			old:    &Field{Name: "author_id", Type: &Type{T: DataTypeBigint}, Ref: &Reference{Table: "users", Column: "id"}},
+			new:    &Field{Name: "author_id", Type: &Type{T: DataTypeBigint}},
+			wantUp: "alter table posts drop constraint posts_author_id_fkey;\n",
+			wantDn: "alter table posts add constraint posts_author_id_fkey foreign key (author_id) references users(id);\n",
+		},
+		{
+			name: "ref action changed",
+			old:  &Field{Name: "author_id", Type: &Type{T: DataTypeBigint}, Ref: &Reference{Table: "users", Column: "id"}},
+			new:  &Field{Name: "author_id", Type: &Type{T: DataTypeBigint}, Ref: &Reference{Table: "users", Column: "id", OnDelete: "cascade"}},
+			wantUp: "alter table posts drop constraint posts_author_id_fkey;\n" +
+				"alter table posts add constraint posts_author_id_fkey foreign key (author_id) references users(id) on delete cascade;\n",
+			wantDn: "alter table posts drop constraint posts_author_id_fkey;\n" +
+				"alter table posts add constraint posts_author_id_fkey foreign key (author_id) references users(id);\n",
+		},
+		{
+			name: "renamed column with ref retargeted",
+			old:  &Field{Name: "user_id", Type: &Type{T: DataTypeBigint}, Ref: &Reference{Table: "users", Column: "id"}},
+			new:  &Field{Name: "owner_id", Type: &Type{T: DataTypeBigint}, Ref: &Reference{Table: "accounts", Column: "id"}},
+			wantUp: "alter table posts drop constraint posts_user_id_fkey;\n" +
+				"alter table posts add constraint posts_owner_id_fkey foreign key (owner_id) references accounts(id);\n",
+			wantDn: "alter table posts drop constraint posts_owner_id_fkey;\n" +
+				"alter table posts add constraint posts_user_id_fkey foreign key (user_id) references users(id);\n",
+		},
+		{
+			name:   "no change",
+			old:    &Field{Name: "author_id", Type: &Type{T: DataTypeBigint}, Ref: &Reference{Table: "users", Column: "id"}},
+			new:    &Field{Name: "author_id", Type: &Type{T: DataTypeBigint}, Ref: &Reference{Table: "users", Column: "id"}},
+			wantUp: "",
+			wantDn: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			up, dn := &gen{}, &gen{}
+			diffRef(up, dn, "posts", c.old, c.new)
+
+			if got := up.String(); got != c.wantUp {
+				t.Errorf("up = %q, want %q", got, c.wantUp)
+			}
+			if got := dn.String(); got != c.wantDn {
+				t.Errorf("dn = %q, want %q", got, c.wantDn)
+			}
+		})
+	}
+}
+
+func TestDiffIndexSet(t *testing.T) {
+	up, dn := &gen{}, &gen{}
+	diffIndexSet(up, dn, "posts", []Index{{"a"}}, []Index{{"a"}, {"b"}}, false)
+
+	if !strings.Contains(up.String(), "create index posts_b_idx on posts (b);") {
+		t.Errorf("up missing new index, got %q", up.String())
+	}
+	if !strings.Contains(dn.String(), "drop index posts_b_idx;") {
+		t.Errorf("dn missing drop of new index, got %q", dn.String())
+	}
+	if strings.Contains(up.String(), "posts_a_idx") {
+		t.Errorf("up should not touch unchanged index, got %q", up.String())
+	}
+}
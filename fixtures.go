@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-yaml/yaml"
+)
+
+// writeFixtures validates every table's 'seeds:' rows against its parsed
+// fields and writes one YAML file per table, in the layout consumed by
+// gopkg.in/testfixtures.v2, into dir. Tables with no seeds are skipped.
+func writeFixtures(data *Metadata, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, t := range data.Tables {
+		if len(t.Seeds) == 0 {
+			continue
+		}
+
+		rows, err := validateSeeds(t, now)
+		if err != nil {
+			return err
+		}
+
+		b, err := yaml.Marshal(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, t.Name+".yaml"), b, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSeeds checks t's seed rows against its fields (unknown columns,
+// missing required columns, enum membership) and resolves 'now' defaults
+// on timestamptz columns, returning one ordered record per row.
+func validateSeeds(t *Table, now time.Time) ([]yaml.MapSlice, error) {
+	var rows []yaml.MapSlice
+
+	for i, seed := range t.Seeds {
+		provided := map[string]interface{}{}
+		for _, item := range seed {
+			col := item.Key.(string)
+			if findField(t, col) == nil {
+				return nil, fmt.Errorf("%s: seed #%d: unknown column '%s'", t.Name, i+1, col)
+			}
+			provided[col] = item.Value
+		}
+
+		var row yaml.MapSlice
+		for _, f := range t.Fields {
+			val, ok := provided[f.Name]
+			if !ok {
+				if !f.Nullable && f.Default == nil {
+					return nil, fmt.Errorf("%s: seed #%d: missing required column '%s'", t.Name, i+1, f.Name)
+				}
+				continue
+			}
+
+			if f.Type.IsEnum {
+				s, _ := val.(string)
+				if !containsString(f.Type.EnumValues, s) {
+					return nil, fmt.Errorf("%s: seed #%d: '%s' is not a valid value for enum %s", t.Name, i+1, s, f.Type.T)
+				}
+			}
+
+			if f.Type.T == DataTypeTimestamptz {
+				if s, ok := val.(string); ok && s == "now" {
+					val = now.Format(time.RFC3339)
+				}
+			}
+
+			row = append(row, yaml.MapItem{Key: f.Name, Value: val})
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-yaml/yaml"
+)
+
+func seedRow(pairs ...interface{}) yaml.MapSlice {
+	var row yaml.MapSlice
+	for i := 0; i+1 < len(pairs); i += 2 {
+		row = append(row, yaml.MapItem{Key: pairs[i], Value: pairs[i+1]})
+	}
+	return row
+}
+
+func TestValidateSeeds(t *testing.T) {
+	now := time.Date(2026, 7, 27, 12, 0, 0, 0, time.UTC)
+
+	roleEnum := &Type{T: "role_enum", IsEnum: true, EnumValues: []string{"admin", "member"}}
+
+	table := &Table{
+		Name: "users",
+		Fields: []*Field{
+			{Name: "id", Type: &Type{T: DataTypeSerial}, PK: true},
+			{Name: "email", Type: &Type{T: DataTypeVarchar}},
+			{Name: "role", Type: roleEnum},
+			{Name: "nickname", Type: &Type{T: DataTypeVarchar}, Nullable: true},
+			{Name: "created_at", Type: &Type{T: DataTypeTimestamptz}},
+		},
+	}
+
+	t.Run("valid row resolves now and carries through values", func(t *testing.T) {
+		table.Seeds = []yaml.MapSlice{
+			seedRow("id", 1, "email", "a@example.com", "role", "admin", "created_at", "now"),
+		}
+
+		rows, err := validateSeeds(table, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(rows) != 1 {
+			t.Fatalf("expected 1 row, got %d", len(rows))
+		}
+
+		got := map[string]interface{}{}
+		for _, item := range rows[0] {
+			got[item.Key.(string)] = item.Value
+		}
+		if got["created_at"] != now.Format(time.RFC3339) {
+			t.Errorf("created_at = %v, want resolved now", got["created_at"])
+		}
+		if got["email"] != "a@example.com" {
+			t.Errorf("email = %v, want a@example.com", got["email"])
+		}
+		if _, ok := got["nickname"]; ok {
+			t.Errorf("nullable column with no value should be omitted, got %v", got["nickname"])
+		}
+	})
+
+	t.Run("unknown column errors", func(t *testing.T) {
+		table.Seeds = []yaml.MapSlice{seedRow("id", 1, "bogus", "x")}
+
+		_, err := validateSeeds(table, now)
+		if err == nil || !strings.Contains(err.Error(), "unknown column") {
+			t.Fatalf("expected unknown column error, got %v", err)
+		}
+	})
+
+	t.Run("missing required column errors", func(t *testing.T) {
+		table.Seeds = []yaml.MapSlice{seedRow("id", 1)}
+
+		_, err := validateSeeds(table, now)
+		if err == nil || !strings.Contains(err.Error(), "missing required column") {
+			t.Fatalf("expected missing required column error, got %v", err)
+		}
+	})
+
+	t.Run("invalid enum value errors", func(t *testing.T) {
+		table.Seeds = []yaml.MapSlice{
+			seedRow("id", 1, "email", "a@example.com", "role", "superadmin", "created_at", "now"),
+		}
+
+		_, err := validateSeeds(table, now)
+		if err == nil || !strings.Contains(err.Error(), "not a valid value for enum") {
+			t.Fatalf("expected enum validation error, got %v", err)
+		}
+	})
+}
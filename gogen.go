@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// namingConvention controls how goTypeName/goArgName tokenize a source
+// identifier into a Go identifier: "snake_case" (default) splits on '_',
+// "camelCase" treats the identifier as already word-cased. It is set once
+// per writeGo call, which pgen never does concurrently.
+var namingConvention = "snake_case"
+
+// writeGo renders typed structs, enum constants and sqlx-backed CRUD
+// helpers for data into dir/models.go, under the given package name.
+// naming selects how source identifiers are tokenized into Go
+// identifiers ("snake_case" or "camelCase"); "" defaults to snake_case.
+// d selects the identifier quoting and placeholder style used by the
+// generated queries, matching the dialect the SQL itself was rendered
+// with; "" (nil) defaults to postgres.
+func writeGo(data *Metadata, dir string, pkg string, naming string, d Dialect) error {
+	if naming != "" {
+		namingConvention = naming
+	}
+
+	if d == nil {
+		d = dialectPostgres{}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	g := &gen{}
+	renderGo(data, pkg, g, d)
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "models.go"), src, 0644)
+}
+
+func renderGo(data *Metadata, pkg string, g *gen, d Dialect) {
+	g.Pf("// Code generated by pgen, DO NOT EDIT.").Ln().Ln()
+	g.Pf("package %s", pkg).Ln().Ln()
+
+	g.P("import (").Ln()
+	g.P("\t\"database/sql\"").Ln()
+	g.P("\t\"time\"").Ln().Ln()
+	g.P("\t\"github.com/jmoiron/sqlx\"").Ln()
+	g.P(")").Ln().Ln()
+
+	for _, e := range data.Enums {
+		if len(e.Values) == 0 {
+			continue
+		}
+		renderGoEnum(g, e)
+	}
+
+	for _, t := range data.Tables {
+		if len(t.Fields) == 0 {
+			continue
+		}
+		renderGoTable(g, t, d)
+	}
+}
+
+func renderGoEnum(g *gen, e *Enum) {
+	name := goTypeName(e.Name)
+	recv := strings.ToLower(name[:1])
+
+	g.Pf("type %s string", name).Ln().Ln()
+
+	g.P("const (").Ln()
+	for _, v := range e.Values {
+		g.Pf("\t%s%s %s = \"%s\"", name, goTypeName(v), name, v).Ln()
+	}
+	g.P(")").Ln().Ln()
+
+	g.Pf("func (%s %s) Values() []%s {", recv, name, name).Ln()
+	g.Pf("\treturn []%s{", name).Ln()
+	for _, v := range e.Values {
+		g.Pf("\t\t%s%s,", name, goTypeName(v)).Ln()
+	}
+	g.P("\t}").Ln()
+	g.P("}").Ln().Ln()
+}
+
+func renderGoTable(g *gen, t *Table, d Dialect) {
+	structName := goTypeName(t.Name)
+
+	g.Pf("type %s struct {", structName).Ln()
+	for _, f := range t.Fields {
+		g.Pf("\t%s %s `db:\"%s\"`", goTypeName(f.Name), goFieldType(f), f.Name).Ln()
+	}
+	g.P("}").Ln().Ln()
+
+	renderGoInsert(g, t, structName, d)
+
+	if pk := pkField(t); pk != nil {
+		renderGoGetByID(g, t, structName, pk, d)
+		renderGoUpdateByID(g, t, structName, pk, d)
+		renderGoDeleteByID(g, t, structName, pk, d)
+	}
+
+	for _, idx := range t.Uniques {
+		renderGoGetBy(g, t, structName, idx, d)
+	}
+	for _, idx := range t.Indexes {
+		renderGoGetBy(g, t, structName, idx, d)
+	}
+}
+
+func renderGoInsert(g *gen, t *Table, structName string, d Dialect) {
+	var cols, placeholders []string
+	for _, f := range t.Fields {
+		if f.PK && f.Type.T == DataTypeSerial {
+			continue
+		}
+		cols = append(cols, d.QuoteIdent(f.Name))
+		placeholders = append(placeholders, ":"+f.Name)
+	}
+
+	query := fmt.Sprintf("insert into %s (%s) values (%s)",
+		d.QuoteIdent(t.Name), strings.Join(cols, ", "), strings.Join(placeholders, ", "))
+
+	g.Pf("func Insert%s(db *sqlx.DB, m *%s) error {", structName, structName).Ln()
+	g.Pf("\t_, err := db.NamedExec(%q, m)", query).Ln()
+	g.P("\treturn err").Ln()
+	g.P("}").Ln().Ln()
+}
+
+func renderGoGetByID(g *gen, t *Table, structName string, pk *Field, d Dialect) {
+	query := fmt.Sprintf("select * from %s where %s = %s", d.QuoteIdent(t.Name), d.QuoteIdent(pk.Name), d.Placeholder(1))
+
+	g.Pf("func Get%sByID(db *sqlx.DB, id %s) (*%s, error) {", structName, goBaseType(pk.Type), structName).Ln()
+	g.Pf("\tvar m %s", structName).Ln()
+	g.Pf("\tif err := db.Get(&m, %q, id); err != nil {", query).Ln()
+	g.P("\t\treturn nil, err").Ln()
+	g.P("\t}").Ln()
+	g.P("\treturn &m, nil").Ln()
+	g.P("}").Ln().Ln()
+}
+
+func renderGoUpdateByID(g *gen, t *Table, structName string, pk *Field, d Dialect) {
+	var sets []string
+	for _, f := range t.Fields {
+		if f.Name == pk.Name {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = :%s", d.QuoteIdent(f.Name), f.Name))
+	}
+
+	query := fmt.Sprintf("update %s set %s where %s = :%s",
+		d.QuoteIdent(t.Name), strings.Join(sets, ", "), d.QuoteIdent(pk.Name), pk.Name)
+
+	g.Pf("func Update%sByID(db *sqlx.DB, m *%s) error {", structName, structName).Ln()
+	g.Pf("\t_, err := db.NamedExec(%q, m)", query).Ln()
+	g.P("\treturn err").Ln()
+	g.P("}").Ln().Ln()
+}
+
+func renderGoDeleteByID(g *gen, t *Table, structName string, pk *Field, d Dialect) {
+	query := fmt.Sprintf("delete from %s where %s = %s", d.QuoteIdent(t.Name), d.QuoteIdent(pk.Name), d.Placeholder(1))
+
+	g.Pf("func Delete%sByID(db *sqlx.DB, id %s) error {", structName, goBaseType(pk.Type)).Ln()
+	g.Pf("\t_, err := db.Exec(%q, id)", query).Ln()
+	g.P("\treturn err").Ln()
+	g.P("}").Ln().Ln()
+}
+
+func renderGoGetBy(g *gen, t *Table, structName string, idx Index, d Dialect) {
+	var nameParts, conds, params, args []string
+	for i, col := range idx {
+		nameParts = append(nameParts, goTypeName(col))
+		conds = append(conds, fmt.Sprintf("%s = %s", d.QuoteIdent(col), d.Placeholder(i+1)))
+
+		typ := "interface{}"
+		if f := findField(t, col); f != nil {
+			typ = goBaseType(f.Type)
+		}
+		arg := goArgName(col)
+		params = append(params, fmt.Sprintf("%s %s", arg, typ))
+		args = append(args, arg)
+	}
+
+	fnName := fmt.Sprintf("Get%sBy%s", structName, strings.Join(nameParts, ""))
+	query := fmt.Sprintf("select * from %s where %s", d.QuoteIdent(t.Name), strings.Join(conds, " and "))
+
+	g.Pf("func %s(db *sqlx.DB, %s) (*%s, error) {", fnName, strings.Join(params, ", "), structName).Ln()
+	g.Pf("\tvar m %s", structName).Ln()
+	g.Pf("\tif err := db.Get(&m, %q, %s); err != nil {", query, strings.Join(args, ", ")).Ln()
+	g.P("\t\treturn nil, err").Ln()
+	g.P("\t}").Ln()
+	g.P("\treturn &m, nil").Ln()
+	g.P("}").Ln().Ln()
+}
+
+func findField(t *Table, name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func pkField(t *Table) *Field {
+	for _, f := range t.Fields {
+		if f.PK {
+			return f
+		}
+	}
+	return nil
+}
+
+// goTypeName converts a source identifier into a Go-exported PascalCase
+// identifier, tokenizing according to namingConvention: "snake_case"
+// splits on '_' (e.g. "user_id" -> "UserID"); "camelCase" splits on '_'
+// AND on lower-to-upper case boundaries, so it also tokenizes an
+// already-camelCased identifier (e.g. "userId" -> "UserID", acronym words
+// like "id"/"url"/"api" are upper-cased regardless of token boundary) as
+// well as this DSL's own snake_case field names (e.g. "created_at" ->
+// "CreatedAt").
+func goTypeName(s string) string {
+	var parts []string
+	if namingConvention == "camelCase" {
+		parts = splitCamelWords(s)
+	} else {
+		parts = strings.Split(s, "_")
+	}
+
+	var b strings.Builder
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if upper := strings.ToUpper(part); upper == "ID" || upper == "URL" || upper == "API" {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// splitCamelWords splits s into words on '_' and on lower-to-upper case
+// boundaries, so it tokenizes both snake_case and camelCase identifiers.
+func splitCamelWords(s string) []string {
+	var words []string
+	var cur []rune
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if r == '_' {
+			if len(cur) > 0 {
+				words = append(words, string(cur))
+				cur = nil
+			}
+			continue
+		}
+
+		if i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(runes[i-1]) {
+			words = append(words, string(cur))
+			cur = nil
+		}
+
+		cur = append(cur, r)
+	}
+	if len(cur) > 0 {
+		words = append(words, string(cur))
+	}
+
+	return words
+}
+
+// goArgName converts a snake_case identifier into a lowerCamelCase
+// identifier suitable for a Go function parameter, e.g. "user_id" -> "userID".
+func goArgName(s string) string {
+	name := goTypeName(s)
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// goBaseType maps a pgen data type to the Go type used for a non-nullable
+// field of that type.
+func goBaseType(t *Type) string {
+	if t.IsEnum {
+		return goTypeName(t.T)
+	}
+
+	switch t.T {
+	case DataTypeInteger:
+		return "int32"
+	case DataTypeBigint, DataTypeSerial:
+		return "int64"
+	case DataTypeVarchar, DataTypeText:
+		return "string"
+	case DataTypeBool:
+		return "bool"
+	case DataTypeDouble:
+		return "float64"
+	case DataTypeTime, DataTypeTimestamptz:
+		return "time.Time"
+	case "jsonb":
+		return "[]byte"
+	default:
+		return "interface{}"
+	}
+}
+
+// goFieldType maps a field to its Go struct field type, accounting for
+// nullability: scalar types use database/sql's sql.Null* wrappers, enum
+// and jsonb types use a plain pointer.
+func goFieldType(f *Field) string {
+	base := goBaseType(f.Type)
+	if !f.Nullable {
+		return base
+	}
+
+	if f.Type.IsEnum {
+		return "*" + base
+	}
+
+	switch f.Type.T {
+	case DataTypeInteger:
+		return "sql.NullInt32"
+	case DataTypeBigint, DataTypeSerial:
+		return "sql.NullInt64"
+	case DataTypeVarchar, DataTypeText:
+		return "sql.NullString"
+	case DataTypeBool:
+		return "sql.NullBool"
+	case DataTypeDouble:
+		return "sql.NullFloat64"
+	case DataTypeTime, DataTypeTimestamptz:
+		return "sql.NullTime"
+	default:
+		return "*" + base
+	}
+}
@@ -14,29 +14,89 @@ import (
 )
 
 var model = flag.String("model", "", "model file")
+var outGoDir = flag.String("out-go", "", "directory to write generated Go structs and sqlx repositories to")
+var pkgName = flag.String("package", "model", "package name for the generated Go code")
+
+var prevModel = flag.String("prev", "", "previous model file; enables schema-diff mode, writing a migration from -prev to -model instead of a full create-table script")
+var migrationsDir = flag.String("migrations-dir", ".", "directory to write the migration file(s) to in -prev mode")
+var withDown = flag.Bool("down", false, "also write a down migration in -prev mode")
+
+var dialectName = flag.String("dialect", "postgres", "SQL dialect to render: postgres, mysql, or sqlite")
+
+var configPath = flag.String("config", "", "config file describing a full run (models, outputs, dialect, package, naming); when set, this replaces -model/-out-go/-package/-dialect")
+
+var fixturesDir = flag.String("fixtures", "", "directory to write testfixtures.v2 seed YAML files to, one per table with a 'seeds' block")
 
 func main() {
 	flag.Parse()
 
+	if *configPath != "" {
+		exitIfErr(runConfig(*configPath, *prevModel, *withDown, *migrationsDir))
+		return
+	}
+
 	if *model == "" {
 		return
 	}
 
-	exitIfErr(checkFile(*model))
+	data, err := parseModel(*model)
+	exitIfErr(err)
+
+	if *prevModel != "" {
+		warnIfDialectIgnoredByDiff(*dialectName)
+
+		prevData, err := parseModel(*prevModel)
+		exitIfErr(err)
+
+		exitIfErr(writeMigration(prevData, data, *migrationsDir, *withDown))
+		return
+	}
 
-	raw, err := readFile(*model)
+	d, err := dialectByName(*dialectName)
 	exitIfErr(err)
 
-	// parse to get structured data
+	exitIfErr(render(data, os.Stdout, d))
+
+	if *outGoDir != "" {
+		exitIfErr(writeGo(data, *outGoDir, *pkgName, "", d))
+	}
+
+	if *fixturesDir != "" {
+		exitIfErr(writeFixtures(data, *fixturesDir))
+	}
+}
+
+// parseModel reads and parses the model file at path into a Metadata tree.
+func parseModel(path string) (*Metadata, error) {
+	if err := checkFile(path); err != nil {
+		return nil, err
+	}
+
+	raw, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
 	p := parser{
 		Data: &Metadata{
 			Enums:  []*Enum{},
 			Tables: []*Table{},
 		},
 	}
-	exitIfErr(p.parse(raw))
+	if err := p.parse(raw); err != nil {
+		return nil, err
+	}
 
-	exitIfErr(render(p.Data, os.Stdout))
+	return p.Data, nil
+}
+
+// warnIfDialectIgnoredByDiff warns on stderr when dialect is anything but
+// postgres's default, since -prev/-config diff mode always renders
+// postgres-syntax migrations regardless of -dialect.
+func warnIfDialectIgnoredByDiff(dialect string) {
+	if dialect != "" && dialect != "postgres" {
+		fmt.Fprintf(os.Stderr, "warning: -prev migrations always use postgres syntax; -dialect %q is ignored\n", dialect)
+	}
 }
 
 func exitIfErr(e error) {
@@ -75,6 +135,11 @@ func readFile(path string) (yaml.MapSlice, error) {
 
 type parser struct {
 	Data *Metadata
+
+	// DefaultNow is the literal substituted for a timestamptz field's
+	// 'default: now'. Defaults to "current_timestamp"; overridable via
+	// Config's 'default_now'.
+	DefaultNow string
 }
 
 type Metadata struct {
@@ -82,27 +147,31 @@ type Metadata struct {
 	Tables []*Table
 }
 
-func (p *parser) parse(raw yaml.MapSlice) error {
-	parse := func(raw yaml.MapSlice, f func(t string, s yaml.MapItem) error) error {
-		for _, s := range raw {
-			attrs := s.Value.(yaml.MapSlice)
-			if len(attrs) == 0 {
-				continue
-			}
+// walkTypes iterates raw's top-level entries, calling f with each entry's
+// 'type' attribute.
+func (p *parser) walkTypes(raw yaml.MapSlice, f func(t string, s yaml.MapItem) error) error {
+	for _, s := range raw {
+		attrs := s.Value.(yaml.MapSlice)
+		if len(attrs) == 0 {
+			continue
+		}
 
-			if attrs[0].Key.(string) != "type" {
-				return fmt.Errorf("%s: the first attribute must be 'type'", s.Key.(string))
-			}
+		if attrs[0].Key.(string) != "type" {
+			return fmt.Errorf("%s: the first attribute must be 'type'", s.Key.(string))
+		}
 
-			if err := f(attrs[0].Value.(string), s); err != nil {
-				return err
-			}
+		if err := f(attrs[0].Value.(string), s); err != nil {
+			return err
 		}
-		return nil
 	}
+	return nil
+}
 
-	// parse enum
-	if err := parse(raw, func(t string, s yaml.MapItem) error {
+// parseEnums parses raw's 'enum' entries into p.Data.Enums. Call once per
+// model file before parseTables, so that a table's enum-typed field can
+// resolve an enum declared in a different file.
+func (p *parser) parseEnums(raw yaml.MapSlice) error {
+	return p.walkTypes(raw, func(t string, s yaml.MapItem) error {
 		if t != "enum" {
 			return nil
 		}
@@ -114,12 +183,14 @@ func (p *parser) parse(raw yaml.MapSlice) error {
 		p.Data.Enums = append(p.Data.Enums, e)
 
 		return nil
-	}); err != nil {
-		return err
-	}
+	})
+}
 
-	// after all other types parsed, parse table
-	if err := parse(raw, func(t string, s yaml.MapItem) error {
+// parseTables parses raw's 'table' entries into p.Data.Tables. Call after
+// parseEnums has run for every model file being merged, and call
+// validateRefs once every file's tables have been parsed.
+func (p *parser) parseTables(raw yaml.MapSlice) error {
+	return p.walkTypes(raw, func(t string, s yaml.MapItem) error {
 		if t != "table" {
 			return nil
 		}
@@ -131,13 +202,86 @@ func (p *parser) parse(raw yaml.MapSlice) error {
 		p.Data.Tables = append(p.Data.Tables, table)
 
 		return nil
-	}); err != nil {
+	})
+}
+
+func (p *parser) parse(raw yaml.MapSlice) error {
+	if err := p.parseEnums(raw); err != nil {
 		return err
 	}
 
+	if err := p.parseTables(raw); err != nil {
+		return err
+	}
+
+	// refs may point at tables declared later in the file, so they can
+	// only be validated once every table has been parsed
+	if err := p.validateRefs(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateRefs checks that every field's 'ref'/'fk' attribute points at a
+// table and column that actually exist, and that the two sides' types are
+// compatible (integer<->integer, bigint<->bigint/serial).
+func (p *parser) validateRefs() error {
+	for _, t := range p.Data.Tables {
+		for _, f := range t.Fields {
+			if f.Ref == nil {
+				continue
+			}
+
+			target := findTable(p.Data.Tables, f.Ref.Table)
+			if target == nil {
+				return fmt.Errorf("%s.%s: ref target table '%s' does not exist", t.Name, f.Name, f.Ref.Table)
+			}
+
+			targetField := findField(target, f.Ref.Column)
+			if targetField == nil {
+				return fmt.Errorf("%s.%s: ref target column '%s.%s' does not exist", t.Name, f.Name, f.Ref.Table, f.Ref.Column)
+			}
+
+			if !refTypesCompatible(f.Type, targetField.Type) {
+				return fmt.Errorf("%s.%s: ref target '%s.%s' has incompatible type '%s'", t.Name, f.Name, f.Ref.Table, f.Ref.Column, targetField.Type.T)
+			}
+		}
+	}
+	return nil
+}
+
+func findTable(tables []*Table, name string) *Table {
+	for _, t := range tables {
+		if t.Name == name {
+			return t
+		}
+	}
 	return nil
 }
 
+// refTypesCompatible reports whether a and b may be linked by a foreign
+// key: integer only matches integer, bigint matches bigint or serial.
+func refTypesCompatible(a, b *Type) bool {
+	norm := func(t *Type) string {
+		if t.T == DataTypeSerial {
+			return DataTypeBigint
+		}
+		return t.T
+	}
+	return norm(a) == norm(b)
+}
+
+// defaultNow returns the literal to substitute for a timestamptz field's
+// 'default: now', falling back to "current_timestamp" when DefaultNow
+// was left unset.
+func (p *parser) defaultNow() string {
+	if p.DefaultNow != "" {
+		return p.DefaultNow
+	}
+	return "current_timestamp"
+}
+
 func (p *parser) parseEnum(s yaml.MapItem) (*Enum, error) {
 	e := Enum{
 		Name:   s.Key.(string),
@@ -170,6 +314,8 @@ func (p *parser) parseTable(s yaml.MapItem) (*Table, error) {
 			t.DB = attr.Value.(string)
 		case "comment":
 			t.Comment = attr.Value.(string)
+		case "renamed_from":
+			t.RenamedFrom = attr.Value.(string)
 		case "fields":
 			if attr.Value == nil {
 				continue
@@ -187,6 +333,14 @@ func (p *parser) parseTable(s yaml.MapItem) (*Table, error) {
 			t.Uniques = p.parseIndexes(attr.Value)
 		case "indexes":
 			t.Indexes = p.parseIndexes(attr.Value)
+		case "seeds":
+			if attr.Value == nil {
+				continue
+			}
+
+			for _, row := range attr.Value.([]interface{}) {
+				t.Seeds = append(t.Seeds, row.(yaml.MapSlice))
+			}
 		}
 	}
 
@@ -195,9 +349,32 @@ func (p *parser) parseTable(s yaml.MapItem) (*Table, error) {
 		return nil, fmt.Errorf("%s: db should be provided", t.Name)
 	}
 
+	// a referencing column needs an index to avoid locking/scan costs on
+	// the other side of the foreign key; add one unless the user already
+	// declared an index/unique on it
+	for _, f := range t.Fields {
+		if f.Ref != nil && !hasIndexOnColumn(&t, f.Name) {
+			t.Indexes = append(t.Indexes, Index{f.Name})
+		}
+	}
+
 	return &t, nil
 }
 
+func hasIndexOnColumn(t *Table, col string) bool {
+	for _, idx := range t.Uniques {
+		if len(idx) == 1 && idx[0] == col {
+			return true
+		}
+	}
+	for _, idx := range t.Indexes {
+		if len(idx) == 1 && idx[0] == col {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *parser) parseIndexes(in interface{}) []Index {
 	if in == nil {
 		return nil
@@ -238,7 +415,7 @@ func (p *parser) parseField(in interface{}) (*Field, error) {
 				if val := item.Value.(string); val != "now" {
 					return nil, fmt.Errorf("%s: invalid default value '%s'", f.Name, val)
 				}
-				f.Default = "current_timestamp"
+				f.Default = p.defaultNow()
 			default:
 				if !f.Type.IsEnum {
 					return nil, fmt.Errorf("%s: data type '%s' can not have 'default' attribute", f.Name, f.Type.T)
@@ -263,6 +440,32 @@ func (p *parser) parseField(in interface{}) (*Field, error) {
 				return nil, fmt.Errorf("%s: primary key must be integer, bigint, serial", f.Name)
 			}
 			f.PK = item.Value.(bool)
+		case "renamed_from":
+			f.RenamedFrom = item.Value.(string)
+		case "ref", "fk":
+			ref, err := parseRef(item.Value.(string))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", f.Name, err.Error())
+			}
+			f.Ref = ref
+		case "on_delete":
+			if f.Ref == nil {
+				return nil, fmt.Errorf("%s: 'on_delete' requires 'ref'/'fk' to be set first", f.Name)
+			}
+			action, err := parseRefAction(item.Value.(string))
+			if err != nil {
+				return nil, fmt.Errorf("%s: on_delete: %s", f.Name, err.Error())
+			}
+			f.Ref.OnDelete = action
+		case "on_update":
+			if f.Ref == nil {
+				return nil, fmt.Errorf("%s: 'on_update' requires 'ref'/'fk' to be set first", f.Name)
+			}
+			action, err := parseRefAction(item.Value.(string))
+			if err != nil {
+				return nil, fmt.Errorf("%s: on_update: %s", f.Name, err.Error())
+			}
+			f.Ref.OnUpdate = action
 		default:
 			return nil, fmt.Errorf("%s: invalid attribute: %s", f.Name, key)
 		}
@@ -282,9 +485,36 @@ func (p *parser) parseField(in interface{}) (*Field, error) {
 		}
 	}
 
+	if f.Ref != nil {
+		switch f.Type.T {
+		case DataTypeInteger, DataTypeBigint, DataTypeSerial:
+		default:
+			return nil, fmt.Errorf("%s: ref/fk must be integer, bigint or serial", f.Name)
+		}
+	}
+
 	return &f, nil
 }
 
+// parseRef parses a 'ref'/'fk' attribute value of the form
+// "other_table.other_column".
+func parseRef(s string) (*Reference, error) {
+	parts := strings.SplitN(s, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid ref '%s', expected 'other_table.other_column'", s)
+	}
+	return &Reference{Table: parts[0], Column: parts[1]}, nil
+}
+
+func parseRefAction(s string) (string, error) {
+	switch s {
+	case "cascade", "set null", "restrict", "no action":
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid action '%s', expected one of: cascade, set null, restrict, no action", s)
+	}
+}
+
 func (p *parser) parseDataType(t string) (*Type, error) {
 	switch t {
 	case "i32":
@@ -307,8 +537,9 @@ func (p *parser) parseDataType(t string) (*Type, error) {
 		for _, e := range p.Data.Enums {
 			if t == e.Name {
 				return &Type{
-					T:      t,
-					IsEnum: true,
+					T:          t,
+					IsEnum:     true,
+					EnumValues: e.Values,
 				}, nil
 			}
 		}
@@ -317,36 +548,91 @@ func (p *parser) parseDataType(t string) (*Type, error) {
 	}
 }
 
-func render(data *Metadata, w io.Writer) error {
+// renderFieldDDL writes the column definition fragment for f (name, type,
+// size, default, nullability and primary key) using postgres syntax,
+// without trailing punctuation, so it can be reused both inside a
+// `create table` and in an `add column` migration statement. -prev diff
+// mode always speaks postgres, independent of -dialect.
+func renderFieldDDL(g *gen, f *Field) {
+	g.P(f.Name, f.Type.T)
+
+	if f.Size > 0 {
+		switch f.Type.T {
+		case DataTypeVarchar:
+			g.Pf("(%d)", f.Size)
+		}
+	}
+
+	if f.Default != nil {
+		g.Pf(" default %s", (dialectPostgres{}).DefaultLiteral(f))
+	}
+
+	if !f.Nullable && !f.PK {
+		g.P(" not null")
+	}
+
+	if f.PK {
+		g.P(" primary key")
+	}
+
+	g.P(renderRefClause(dialectPostgres{}, f))
+}
+
+// renderRefClause renders the `references ...` constraint for f, or ""
+// when f has no 'ref'/'fk' attribute.
+func renderRefClause(d Dialect, f *Field) string {
+	if f.Ref == nil {
+		return ""
+	}
+
+	s := fmt.Sprintf(" references %s(%s)", d.QuoteIdent(f.Ref.Table), d.QuoteIdent(f.Ref.Column))
+	if f.Ref.OnDelete != "" {
+		s += fmt.Sprintf(" on delete %s", f.Ref.OnDelete)
+	}
+	if f.Ref.OnUpdate != "" {
+		s += fmt.Sprintf(" on update %s", f.Ref.OnUpdate)
+	}
+	return s
+}
+
+// renderFKConstraint renders the trailing table-level foreign key clause
+// for f (no leading/trailing comma or newline), for use by dialects whose
+// column-level REFERENCES clause is parsed but not enforced (MySQL/InnoDB
+// silently ignores it). f must have a non-nil Ref.
+func renderFKConstraint(d Dialect, t *Table, f *Field) string {
+	s := fmt.Sprintf("constraint %s foreign key (%s) references %s(%s)",
+		fkConstraintName(t.Name, f.Name), d.QuoteIdent(f.Name), d.QuoteIdent(f.Ref.Table), d.QuoteIdent(f.Ref.Column))
+	if f.Ref.OnDelete != "" {
+		s += fmt.Sprintf(" on delete %s", f.Ref.OnDelete)
+	}
+	if f.Ref.OnUpdate != "" {
+		s += fmt.Sprintf(" on update %s", f.Ref.OnUpdate)
+	}
+	return s
+}
+
+func render(data *Metadata, w io.Writer, d Dialect) error {
 	g := &gen{}
 	g.P("-- Auto generated by pgen, DO NOT MODIFY.").Ln().Ln()
 
 	g.P("-- Enums").Ln().Ln()
 
-	for i, e := range data.Enums {
+	printed := false
+	for _, e := range data.Enums {
 		if len(e.Values) == 0 {
 			continue
 		}
 
-		if i > 0 {
-			g.Ln()
-		}
-
-		g.P("create type", e.Name, "as enum(")
-
-		for j, val := range e.Values {
-			if j > 0 {
-				g.P(", ")
-			}
-			g.Pf("'%s'", val)
+		s := d.RenderEnum(e)
+		if s == "" {
+			continue
 		}
 
-		g.P(");").Ln()
-
-		// comment
-		if e.Comment != "" {
-			g.Pf("comment on type %s is '%s';", e.Name, e.Comment).Ln()
+		if printed {
+			g.Ln()
 		}
+		g.P(s)
+		printed = true
 	}
 
 	g.Ln().P("-- Tables").Ln().Ln()
@@ -360,74 +646,16 @@ func render(data *Metadata, w io.Writer) error {
 			g.Ln()
 		}
 
-		// DDL
-		g.P("create table if not exists", t.Name, "(").Ln()
-
-		for j, f := range t.Fields {
-			g.P(" ", f.Name, f.Type.T)
-
-			// size
-			if f.Size > 0 {
-				switch f.Type.T {
-				case DataTypeVarchar:
-					g.Pf("(%d)", f.Size)
-				}
-			}
-
-			// default
-			if f.Default != nil {
-				switch f.Type.T {
-				case DataTypeVarchar:
-					g.Pf(" default '%s'", f.Default.(string))
-				case DataTypeTimestamptz:
-					g.Pf(" default %s", f.Default.(string))
-				case DataTypeInteger, DataTypeBigint:
-					g.Pf(" default %d", f.Default.(int))
-				case DataTypeBool:
-					g.Pf(" default %t", f.Default.(bool))
-				default:
-					if f.Type.IsEnum {
-						g.Pf(" default '%s'", f.Default.(string))
-					}
-				}
-			}
-
-			if !f.Nullable && !f.PK {
-				g.P(" not null")
-			}
-
-			if f.PK {
-				g.P(" primary key")
-			}
-
-			if j < len(t.Fields)-1 {
-				g.P(",")
-			}
-			g.Ln()
-		}
-
-		g.P(");").Ln()
+		g.P(d.RenderCreateTable(t))
 
-		// indexes
 		for _, index := range t.Uniques {
 			// todo check if column exists
-			g.Pf("create unique index %s_%s_key on %s (%s);", t.Name, strings.Join(index, "_"), t.Name, strings.Join(index, ", ")).Ln()
+			g.P(d.RenderCreateIndex(t, index, true))
 		}
 
 		for _, index := range t.Indexes {
 			// todo check if column exists
-			g.Pf("create index %s_%s_idx on %s (%s);", t.Name, strings.Join(index, "_"), t.Name, strings.Join(index, ", ")).Ln()
-		}
-
-		// comments
-		if t.Comment != "" {
-			g.Pf("comment on table %s is '%s';", t.Name, t.Comment).Ln()
-		}
-
-		for _, f := range t.Fields {
-			if f.Comment != "" {
-				g.Pf("comment on column %s.%s is '%s';", t.Name, f.Name, f.Comment).Ln()
-			}
+			g.P(d.RenderCreateIndex(t, index, false))
 		}
 	}
 
@@ -468,29 +696,43 @@ type Enum struct {
 }
 
 type Table struct {
-	DB      string
-	Name    string
-	Comment string
-	Fields  []*Field
-	Indexes []Index
-	Uniques []Index
+	DB          string
+	Name        string
+	Comment     string
+	Fields      []*Field
+	Indexes     []Index
+	Uniques     []Index
+	RenamedFrom string          // set via the 'renamed_from' attribute, used by -prev to match tables across a rename
+	Seeds       []yaml.MapSlice // set via the 'seeds' attribute, consumed by -fixtures
 }
 
 type Index []string
 
 type Field struct {
-	Name     string
-	Type     *Type
-	Comment  string
-	Nullable bool
-	Default  interface{}
-	Size     int // only 'varchar' has size attribute
-	PK       bool
+	Name        string
+	Type        *Type
+	Comment     string
+	Nullable    bool
+	Default     interface{}
+	Size        int // only 'varchar' has size attribute
+	PK          bool
+	RenamedFrom string     // set via the 'renamed_from' attribute, used by -prev to match columns across a rename
+	Ref         *Reference // set via the 'ref'/'fk' attribute
+}
+
+// Reference describes a foreign key: the field it is attached to points
+// at Table(Column), optionally with ON DELETE / ON UPDATE actions.
+type Reference struct {
+	Table    string
+	Column   string
+	OnDelete string // "", "cascade", "set null", "restrict", "no action"
+	OnUpdate string
 }
 
 type Type struct {
-	T      string
-	IsEnum bool
+	T          string
+	IsEnum     bool
+	EnumValues []string // populated from the matching Enum when IsEnum is true
 }
 
 const (